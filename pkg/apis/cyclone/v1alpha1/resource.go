@@ -25,8 +25,35 @@ const (
 	KVResourceType      = "KV"
 	PVResourceType      = "PV"
 	GeneralResourceType = "General"
+	// PipeResourceType is a virtual resource that publishes files produced by
+	// a stage into a Secret/ConfigMap so later stages can reference them via
+	// `{{Pipes.<key>}}`.
+	PipeResourceType = "Pipe"
 )
 
+// PipeKind is the kind of Kubernetes object a pipe entry is published into.
+type PipeKind string
+
+const (
+	// PipeKindSecret publishes the file content into a Secret.
+	PipeKindSecret PipeKind = "Secret"
+	// PipeKindConfigMap publishes the file content into a ConfigMap.
+	PipeKindConfigMap PipeKind = "ConfigMap"
+)
+
+// PipeSpec describes a single file to be copied out of a stage container
+// once it terminates, and where the content should be published to so that
+// later stages can reference it as `{{Pipes.<Key>}}`.
+type PipeSpec struct {
+	// File is the path of the file inside the stage container to copy out.
+	File string `json:"file"`
+	// Kind is the type of object the file content is published into, Secret or ConfigMap.
+	Kind PipeKind `json:"kind"`
+	// Key is the name the caller assigns to this pipe entry, used both as the
+	// data key in the target object and as the `{{Pipes.Key}}` reference.
+	Key string `json:"key"`
+}
+
 // Resource pull policy
 type ResourcePullPolicy string
 
@@ -47,4 +74,8 @@ type ResourceSpec struct {
 	PullPolicy ResourcePullPolicy `json:"pullPolicy"`
 	// Parameters of the resource
 	Parameters []ParameterItem `json:"parameters"`
+	// Outputs declares files the resource's stage should publish into
+	// Secrets/ConfigMaps once it terminates, so later stages can reference
+	// them via `{{Pipes.<key>}}`. Only meaningful when Type is PipeResourceType.
+	Outputs []PipeSpec `json:"outputs,omitempty"`
 }
\ No newline at end of file
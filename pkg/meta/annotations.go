@@ -0,0 +1,17 @@
+package meta
+
+const (
+	// AnnotationSeccompContainerPrefix is the well-known Kubernetes annotation
+	// prefix used to set a per-container seccomp profile.
+	AnnotationSeccompContainerPrefix = "container.seccomp.security.alpha.kubernetes.io/"
+
+	// AnnotationStageResult holds a stage's key/value execution results on its
+	// pod, synced by the workflow controller into the WorkflowRun status.
+	AnnotationStageResult = "cyclone.dev/stage-result"
+
+	// AnnotationLogBookmarkPrefix prefixes the per-container pod annotation
+	// that records the timestamp of the last log line successfully forwarded
+	// by CollectLog, so a coordinator restart resumes from the right place
+	// instead of double-shipping or losing lines.
+	AnnotationLogBookmarkPrefix = "cyclone.dev/log-bookmark-"
+)
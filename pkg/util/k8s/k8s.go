@@ -0,0 +1,11 @@
+// Package k8s provides the Kubernetes client interface shared across the
+// workflow engine.
+package k8s
+
+import "k8s.io/client-go/kubernetes"
+
+// Interface is the Kubernetes clientset used throughout the coordinator and
+// workflow controller. It is kept as an alias over client-go's generated
+// clientset interface (rather than a concrete type) so tests can substitute
+// k8s.io/client-go/kubernetes/fake.Clientset.
+type Interface = kubernetes.Interface
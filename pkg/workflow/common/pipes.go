@@ -0,0 +1,18 @@
+package common
+
+import "strings"
+
+// pipeRefPrefix is the template prefix used to reference a Pipe resource's
+// published output from a later stage, e.g. `{{Pipes.tls-cert}}`.
+const pipeRefPrefix = "{{Pipes."
+
+// SubstitutePipeRefs replaces `{{Pipes.<key>}}` references in spec with the
+// matching value from pipes, the same way SetResults' key/value outputs are
+// substituted into later stage specs today. References to keys not present
+// in pipes are left untouched.
+func SubstitutePipeRefs(spec string, pipes map[string]string) string {
+	for key, value := range pipes {
+		spec = strings.ReplaceAll(spec, pipeRefPrefix+key+"}}", value)
+	}
+	return spec
+}
@@ -0,0 +1,25 @@
+package common
+
+import "testing"
+
+func TestSubstitutePipeRefs(t *testing.T) {
+	pipes := map[string]string{
+		"tls-cert": "-----BEGIN CERTIFICATE-----",
+		"config":   "key: value",
+	}
+
+	spec := `cert={{Pipes.tls-cert}} cfg={{Pipes.config}} unknown={{Pipes.missing}}`
+	got := SubstitutePipeRefs(spec, pipes)
+	want := `cert=-----BEGIN CERTIFICATE----- cfg=key: value unknown={{Pipes.missing}}`
+
+	if got != want {
+		t.Errorf("SubstitutePipeRefs =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSubstitutePipeRefsNoMatches(t *testing.T) {
+	spec := "no references here"
+	if got := SubstitutePipeRefs(spec, map[string]string{"a": "b"}); got != spec {
+		t.Errorf("SubstitutePipeRefs(%q) = %q, want unchanged", spec, got)
+	}
+}
@@ -1,16 +1,30 @@
 package k8sapi
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	core_v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
@@ -21,9 +35,14 @@ import (
 	"github.com/caicloud/cyclone/pkg/workflow/coordinator/cycloneserver"
 )
 
+// pipeMaxSize is the maximum size of a file published through a Pipe resource,
+// matching the etcd-backed size limit Kubernetes enforces on Secrets/ConfigMaps.
+const pipeMaxSize = 1024 * 1024 // 1MiB
+
 // Executor ...
 type Executor struct {
 	client        k8s.Interface
+	restConfig    *rest.Config
 	metaNamespace string
 	namespace     string
 	podName       string
@@ -31,67 +50,203 @@ type Executor struct {
 }
 
 // NewK8sapiExecutor ...
-func NewK8sapiExecutor(client k8s.Interface, metaNamespace, namespace, pod string, cycloneServer string) *Executor {
+func NewK8sapiExecutor(client k8s.Interface, restConfig *rest.Config, metaNamespace, namespace, pod string, cycloneServer string) *Executor {
 	return &Executor{
 		metaNamespace: metaNamespace,
 		namespace:     namespace,
 		podName:       pod,
 		client:        client,
+		restConfig:    restConfig,
 		cycloneClient: cycloneserver.NewClient(cycloneServer),
 	}
 }
 
-// WaitContainers waits containers that pass selectors.
-func (k *Executor) WaitContainers(expectState common.ContainerState, selectors ...common.ContainerSelector) error {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+// reasonNodeLost prefixes the error WaitContainers returns when the node running
+// the pod was abruptly removed. No terminated container state is synthesized;
+// the caller gets only the error, which wraps ErrNodeLost.
+const reasonNodeLost = "NodeLost"
 
+// ErrNodeLost is returned by WaitContainers when it cannot confirm the
+// expected container state because the node running the pod was lost: the
+// pod went Failed with no container statuses, or the pod object itself
+// disappeared from the apiserver. Callers can check for it with errors.Is to
+// tell this case apart from a plain wait failure.
+var ErrNodeLost = errors.New("node lost")
+
+// WaitContainers waits containers that pass selectors to reach expectState. It
+// watches the pod through a shared informer instead of polling, so many
+// WorkflowRuns executing in parallel share a single watch per pod rather than
+// issuing a Get every second.
+func (k *Executor) WaitContainers(expectState common.ContainerState, selectors ...common.ContainerSelector) error {
 	log.Infof("Starting to wait for containers of pod %s to be %s ...", k.podName, expectState)
-	for range ticker.C {
-		pod, err := k.client.CoreV1().Pods(k.namespace).Get(context.TODO(), k.podName, meta_v1.GetOptions{})
+
+	informer := getPodInformer(k.client, k.namespace, k.podName)
+	if !cache.WaitForCacheSync(sharedInformerStopCh, informer.HasSynced) {
+		return fmt.Errorf("failed to sync informer cache for pod %s", k.podName)
+	}
+
+	resultCh := make(chan error, 1)
+	report := func(pod *core_v1.Pod) {
+		if pod == nil || pod.Name != k.podName {
+			return
+		}
+
+		reached, err := checkContainersState(pod, expectState, selectors)
 		if err != nil {
-			log.WithField("ns", k.namespace).WithField("pod", k.podName).Error("get pod failed")
-			return err
+			select {
+			case resultCh <- err:
+			default:
+			}
+			return
+		}
+		if reached {
+			select {
+			case resultCh <- nil:
+			default:
+			}
 		}
+	}
 
-		var reachGoals = true
-		for _, c := range pod.Spec.Containers {
-			// Skip containers that are not selected.
-			if !common.Pass(c.Name, selectors) {
-				continue
+	reportDeleted := func(obj interface{}) {
+		pod, ok := podFromDeleteEvent(obj)
+		if !ok || pod.Name != k.podName {
+			return
+		}
+
+		// The pod object itself was removed from the apiserver, e.g. because the
+		// node it ran on was abruptly deleted: it never transitions to Failed, it
+		// just disappears. Without this, a waiter for ContainerStateInitialized
+		// would block forever since no further update will ever arrive.
+		log.WithField("pod", pod.Name).Warn("Pod was deleted while waiting for containers, node was likely lost")
+		select {
+		case resultCh <- fmt.Errorf("%s: pod %s was deleted while waiting for containers to be %s: %w", reasonNodeLost, pod.Name, expectState, ErrNodeLost):
+		default:
+		}
+	}
+
+	handle, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*core_v1.Pod); ok {
+				report(pod)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := newObj.(*core_v1.Pod); ok {
+				report(pod)
 			}
+		},
+		DeleteFunc: reportDeleted,
+	})
+	if err != nil {
+		return err
+	}
+	defer informer.RemoveEventHandler(handle) //nolint:errcheck
 
-			var s *core_v1.ContainerStatus
-			for _, cs := range pod.Status.ContainerStatuses {
-				if c.Name == cs.Name {
-					s = &cs
-					break
-				}
-			}
-
-			switch expectState {
-			case common.ContainerStateTerminated:
-				if s == nil || s.State.Terminated == nil {
-					log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not expected status")
-					reachGoals = false
-				}
-			case common.ContainerStateInitialized:
-				if s == nil || (s.State.Running == nil && s.State.Terminated == nil) {
-					log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not in expected status")
-					reachGoals = false
-				}
-			default:
-				return fmt.Errorf("Unsupported state: %s, Only support: %s, %s", expectState, common.ContainerStateTerminated, common.ContainerStateInitialized)
+	// The pod may already satisfy expectState before the informer fires its
+	// first update, so check the current cached state right away.
+	if obj, exists, err := informer.GetStore().GetByKey(k.namespace + "/" + k.podName); err == nil && exists {
+		if pod, ok := obj.(*core_v1.Pod); ok {
+			report(pod)
+		}
+	}
+
+	return <-resultCh
+}
+
+// podFromDeleteEvent extracts the Pod from an informer DeleteFunc callback
+// argument, which may be wrapped in a cache.DeletedFinalStateUnknown tombstone
+// if the delete event was missed and only discovered via relist.
+func podFromDeleteEvent(obj interface{}) (*core_v1.Pod, bool) {
+	if pod, ok := obj.(*core_v1.Pod); ok {
+		return pod, true
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		pod, ok := tombstone.Obj.(*core_v1.Pod)
+		return pod, ok
+	}
+	return nil, false
+}
+
+// checkContainersState reports whether every selected container of pod has
+// reached expectState. It guards against the case where a node is abruptly
+// removed: pod.Status.ContainerStatuses can be empty and State.Terminated nil
+// even though the pod itself is already Failed, which would otherwise make
+// the caller wait forever; that case resolves the wait with ErrNodeLost
+// rather than blocking.
+func checkContainersState(pod *core_v1.Pod, expectState common.ContainerState, selectors []common.ContainerSelector) (bool, error) {
+	if expectState == common.ContainerStateTerminated && pod.Status.Phase == core_v1.PodFailed && len(pod.Status.ContainerStatuses) == 0 {
+		log.WithField("pod", pod.Name).Warn("Pod failed with no container statuses, node was likely lost; resolving wait with ErrNodeLost")
+		return false, fmt.Errorf("%s: pod %s failed with no container statuses: %w", reasonNodeLost, pod.Name, ErrNodeLost)
+	}
+
+	for _, c := range pod.Spec.Containers {
+		// Skip containers that are not selected.
+		if !common.Pass(c.Name, selectors) {
+			continue
+		}
+
+		var s *core_v1.ContainerStatus
+		for i := range pod.Status.ContainerStatuses {
+			if c.Name == pod.Status.ContainerStatuses[i].Name {
+				s = &pod.Status.ContainerStatuses[i]
+				break
 			}
 		}
 
-		if reachGoals {
-			log.WithField("pod", pod.Name).WithField("expected", expectState).Info("All containers reached expected status")
-			return nil
+		switch expectState {
+		case common.ContainerStateTerminated:
+			if s == nil || s.State.Terminated == nil {
+				log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not expected status")
+				return false, nil
+			}
+		case common.ContainerStateInitialized:
+			if s == nil || (s.State.Running == nil && s.State.Terminated == nil) {
+				log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not in expected status")
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("Unsupported state: %s, Only support: %s, %s", expectState, common.ContainerStateTerminated, common.ContainerStateInitialized)
 		}
 	}
 
-	return nil
+	log.WithField("pod", pod.Name).WithField("expected", expectState).Info("All containers reached expected status")
+	return true, nil
+}
+
+// sharedInformerStopCh is never closed; the shared pod informers below live
+// for the lifetime of the coordinator process.
+var sharedInformerStopCh = make(chan struct{})
+
+var (
+	podInformersMu sync.Mutex
+	podInformers   = map[string]cache.SharedIndexInformer{}
+)
+
+// getPodInformer returns the shared informer watching namespace/podName,
+// starting it on first use. All stages running inside one coordinator pod
+// share the same informer per pod so watch count and memory stay bounded.
+func getPodInformer(client k8s.Interface, namespace, podName string) cache.SharedIndexInformer {
+	key := namespace + "/" + podName
+
+	podInformersMu.Lock()
+	defer podInformersMu.Unlock()
+
+	if informer, ok := podInformers[key]; ok {
+		return informer
+	}
+
+	lw := cache.NewListWatchFromClient(
+		client.CoreV1().RESTClient(),
+		"pods",
+		namespace,
+		fields.OneTermEqualSelector("metadata.name", podName),
+	)
+	informer := cache.NewSharedIndexInformer(lw, &core_v1.Pod{}, 0, cache.Indexers{})
+	podInformers[key] = informer
+
+	go informer.Run(sharedInformerStopCh)
+
+	return informer
 }
 
 // GetPod get the stage pod.
@@ -99,28 +254,241 @@ func (k *Executor) GetPod() (*core_v1.Pod, error) {
 	return k.client.CoreV1().Pods(k.namespace).Get(context.TODO(), k.podName, meta_v1.GetOptions{})
 }
 
-// CollectLog collects container logs.
+// logRetryInterval is how long CollectLog waits before reopening the log stream
+// after the apiserver closes it while the container is still running.
+const logRetryInterval = 2 * time.Second
+
+// logPushRetries is how many attempts CollectLog makes in total to forward a
+// chunk of logs to the Cyclone server before it gives up and returns the error.
+const logPushRetries = 3
+
+// logFlushThreshold is the buffered size at which CollectLog forwards
+// accumulated log lines rather than waiting for the stream to end.
+const logFlushThreshold = 64 * 1024
+
+// CollectLog collects container logs. Instead of keeping a single follow
+// stream open for the container's whole lifetime, it reads in a loop using
+// SinceTime bookmarks: if the apiserver closes the stream (e.g. during a
+// rolling control-plane upgrade) or the coordinator restarts mid-stage, it
+// resumes from the last line it successfully forwarded rather than
+// truncating or duplicating logs. The bookmark is persisted as a pod
+// annotation so a coordinator restart also resumes correctly.
 func (k *Executor) CollectLog(container, workflowrun, stage string, close <-chan struct{}) error {
 	log.Infof("Start to collect %s log", container)
-	stream, err := k.client.CoreV1().Pods(k.namespace).GetLogs(k.podName, &core_v1.PodLogOptions{
-		Container: container,
-		Follow:    true,
-	}).Stream(context.TODO())
-	if err != nil {
-		return err
-	}
 
-	defer func() {
+	sinceTime := k.loadLogBookmark(container)
+	for {
+		select {
+		case <-close:
+			return nil
+		default:
+		}
+
+		pod, err := k.GetPod()
+		if err != nil {
+			return err
+		}
+		terminated := containerTerminated(pod, container)
+
+		opts := &core_v1.PodLogOptions{
+			Container:  container,
+			Follow:     !terminated,
+			Timestamps: true,
+		}
+		if sinceTime != nil {
+			opts.SinceTime = sinceTime
+		}
+
+		stream, err := k.client.CoreV1().Pods(k.namespace).GetLogs(k.podName, opts).Stream(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		// sinceTime, passed as forwardLogStream's resumeAfter, is the
+		// fine-grained (nanosecond) bookmark, used to drop
+		// lines the apiserver re-sends because opts.SinceTime only has
+		// second-granularity, so a re-opened stream can overlap the one line we
+		// already forwarded in that second.
+		confirmedTimestamp, fwErr := k.forwardLogStream(stream, workflowrun, stage, container, close, sinceTime)
 		if err := stream.Close(); err != nil {
 			log.WithField("ns", k.namespace).WithField("pod", k.podName).WithField("container", container).Errorf("Fail to close log stream: %v", err)
 		}
-	}()
 
-	err = k.cycloneClient.PushLogStream(k.metaNamespace, workflowrun, stage, container, stream, close)
+		// Only ever advance the bookmark to content forwardLogStream confirms it
+		// actually delivered; a buffered-but-undelivered line must not move the
+		// bookmark past it, or a later resume would skip it for good.
+		if confirmedTimestamp != nil {
+			sinceTime = confirmedTimestamp
+			if err := k.saveLogBookmark(container, *confirmedTimestamp); err != nil {
+				log.WithField("container", container).Warnf("failed to persist log bookmark: %v", err)
+			}
+		}
+		if fwErr != nil && fwErr != io.EOF {
+			return fwErr
+		}
+
+		if terminated {
+			return k.MarkLogEOF(workflowrun, stage, close)
+		}
+
+		select {
+		case <-close:
+			return nil
+		case <-time.After(logRetryInterval):
+		}
+	}
+}
+
+// containerTerminated reports whether container has a terminated status in pod.
+func containerTerminated(pod *core_v1.Pod, container string) bool {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == container {
+			return cs.State.Terminated != nil
+		}
+	}
+	return false
+}
+
+// forwardLogStream reads lines out of stream, buffers them and forwards them
+// to the Cyclone server with retries. resumeAfter, if set, is the bookmark the
+// stream was reopened from: lines timestamped at or before it are dropped
+// rather than re-forwarded, since PodLogOptions.SinceTime only has
+// second-granularity and can make the apiserver resend the line(s) already
+// delivered in that same second. It returns the timestamp of the last line
+// pushLogChunk confirmed it successfully delivered (nil if none was), which is
+// the only thing safe to persist as the next bookmark. A returned error of
+// io.EOF means the stream ended normally.
+func (k *Executor) forwardLogStream(stream io.ReadCloser, workflowrun, stage, container string, close <-chan struct{}, resumeAfter *meta_v1.Time) (*meta_v1.Time, error) {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var buf bytes.Buffer
+	var pendingTimestamp *meta_v1.Time   // timestamp of the last line currently buffered, not yet confirmed delivered
+	var confirmedTimestamp *meta_v1.Time // timestamp through which delivery is confirmed
+
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		data := append([]byte(nil), buf.Bytes()...)
+		buf.Reset()
+		if err := k.pushLogChunk(workflowrun, stage, container, data, close); err != nil {
+			return err
+		}
+		confirmedTimestamp = pendingTimestamp
+		return nil
+	}
+
+	for scanner.Scan() {
+		ts, content := splitLogTimestamp(scanner.Text())
+		if ts != nil {
+			if resumeAfter != nil && !ts.Time.After(resumeAfter.Time) {
+				// Already forwarded (and bookmarked) in a previous attempt.
+				continue
+			}
+			pendingTimestamp = ts
+		}
+		buf.WriteString(content)
+		buf.WriteByte('\n')
+
+		if buf.Len() >= logFlushThreshold {
+			if err := flush(); err != nil {
+				return confirmedTimestamp, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return confirmedTimestamp, err
+	}
+	if err := scanner.Err(); err != nil {
+		return confirmedTimestamp, err
+	}
+
+	return confirmedTimestamp, io.EOF
+}
+
+// pushLogChunk forwards a chunk of already-read log bytes to the Cyclone
+// server, making up to logPushRetries attempts before giving up.
+func (k *Executor) pushLogChunk(workflowrun, stage, container string, data []byte, close <-chan struct{}) error {
+	var err error
+	for attempt := 1; attempt <= logPushRetries; attempt++ {
+		err = k.cycloneClient.PushLogStream(k.metaNamespace, workflowrun, stage, container, bytes.NewReader(data), close)
+		if err == nil {
+			return nil
+		}
+		log.WithField("container", container).Warnf("push log chunk failed (attempt %d/%d): %v", attempt, logPushRetries, err)
+	}
+	return err
+}
+
+// splitLogTimestamp splits a log line produced with PodLogOptions.Timestamps
+// into its RFC3339Nano timestamp and the remaining content. If line has no
+// parseable leading timestamp, it is returned unchanged with a nil timestamp.
+func splitLogTimestamp(line string) (*meta_v1.Time, string) {
+	idx := strings.IndexByte(line, ' ')
+	if idx < 0 {
+		return nil, line
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, line[:idx])
 	if err != nil {
-		return err
+		return nil, line
 	}
-	return nil
+
+	ts := meta_v1.NewTime(t)
+	return &ts, line[idx+1:]
+}
+
+// logBookmarkAnnotation returns the pod annotation key used to persist the
+// last forwarded log timestamp for container.
+func logBookmarkAnnotation(container string) string {
+	return meta.AnnotationLogBookmarkPrefix + container
+}
+
+// loadLogBookmark reads the persisted log bookmark for container from the pod
+// annotations, returning nil if there is none yet.
+func (k *Executor) loadLogBookmark(container string) *meta_v1.Time {
+	pod, err := k.GetPod()
+	if err != nil {
+		return nil
+	}
+
+	value, ok := pod.Annotations[logBookmarkAnnotation(container)]
+	if !ok {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		log.WithField("container", container).Warnf("invalid log bookmark annotation %q: %v", value, err)
+		return nil
+	}
+
+	ts := meta_v1.NewTime(t)
+	return &ts
+}
+
+// saveLogBookmark persists the last forwarded log timestamp for container as a
+// pod annotation, so a coordinator restart resumes from the right place
+// instead of double-shipping or losing lines.
+func (k *Executor) saveLogBookmark(container string, ts meta_v1.Time) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pod, err := k.client.CoreV1().Pods(k.namespace).Get(context.TODO(), k.podName, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		annotations := make(map[string]string, len(pod.Annotations)+1)
+		for key, v := range pod.Annotations {
+			annotations[key] = v
+		}
+		annotations[logBookmarkAnnotation(container)] = ts.Format(time.RFC3339Nano)
+		pod.Annotations = annotations
+
+		_, err = k.client.CoreV1().Pods(k.namespace).Update(context.TODO(), pod, meta_v1.UpdateOptions{})
+		return err
+	})
 }
 
 // MarkLogEOF marks the end of stage logs
@@ -132,19 +500,128 @@ func (k *Executor) MarkLogEOF(workflowrun, stage string, close <-chan struct{})
 	return nil
 }
 
-// CopyFromContainer copy a file/directory from container:path to dst.
+// CopyFromContainer copies a file/directory from container:path to dst by execing
+// into the container and streaming a tar archive back over the Kubernetes API,
+// rather than shelling out to the docker CLI. This keeps the coordinator free of
+// a docker socket dependency and works against any CRI.
 func (k *Executor) CopyFromContainer(container, path, dst string) error {
-	args := []string{"cp", fmt.Sprintf("%s:%s", container, path), dst}
+	reader, writer := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		defer writer.Close()
+		errCh <- k.execInContainer(container, []string{"tar", "cf", "-", path}, nil, writer, os.Stderr)
+	}()
+
+	// Always drain both the tar stream and the exec result: when `tar` isn't on
+	// PATH the exec fails before writing any bytes, so untar sees a plain EOF
+	// and returns nil even though nothing was copied. The exec error, not
+	// untar's, is what tells us whether the fallback is needed.
+	untarErr := untar(reader, path, dst)
+	io.Copy(ioutil.Discard, reader) //nolint:errcheck
+	if execErr := <-errCh; execErr != nil {
+		log.WithField("container", container).WithField("path", path).Warnf("tar exec failed (%v), falling back to cat", execErr)
+		return k.copyFileWithCat(container, path, dst)
+	}
+
+	return untarErr
+}
 
-	cmd := exec.Command("docker", args...)
-	log.WithField("args", args).Info()
-	ret, err := cmd.CombinedOutput()
-	log.WithField("message", string(ret)).WithField("error", err).WithField("container", container).Info("copy file result")
+// copyFileWithCat copies a single file out of a container using `cat`, for
+// containers that don't have `tar` on PATH. File mode/ownership cannot be
+// preserved this way.
+func (k *Executor) copyFileWithCat(container, path, dst string) error {
+	f, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("%s, error: %v", string(ret), err)
+		return err
 	}
+	defer f.Close()
 
-	return nil
+	return k.execInContainer(container, []string{"cat", path}, nil, f, os.Stderr)
+}
+
+// execInContainer execs command in container of the stage pod, streaming stdin/
+// stdout/stderr over SPDY.
+func (k *Executor) execInContainer(container string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	req := k.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(k.podName).
+		Namespace(k.namespace).
+		SubResource("exec")
+	req.VersionedParams(&core_v1.PodExecOptions{
+		Container: container,
+		Command:   command,
+		Stdin:     stdin != nil,
+		Stdout:    stdout != nil,
+		Stderr:    stderr != nil,
+	}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(k.restConfig, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	return executor.Stream(remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+// untar extracts a tar stream produced by `tar cf - <path>` into dst, preserving
+// file mode, ownership and symlinks. path is the source argument tar was given,
+// used to compute relative entry names.
+func untar(r io.Reader, path, dst string) error {
+	tr := tar.NewReader(r)
+	prefix := strings.TrimPrefix(path, "/")
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(hdr.Name, prefix)
+		target := dst
+		if name != "" && name != "." {
+			target = filepath.Join(dst, name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+			if err := os.Chown(target, hdr.Uid, hdr.Gid); err != nil {
+				log.WithField("path", target).Debugf("failed to chown extracted directory: %v", err)
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				log.WithField("path", target).Debugf("failed to lchown extracted symlink: %v", err)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+			if err := os.Chown(target, hdr.Uid, hdr.Gid); err != nil {
+				log.WithField("path", target).Debugf("failed to chown extracted file: %v", err)
+			}
+		}
+	}
 }
 
 // SetResults sets execution results (key-values) to the pod, workflow controller will sync this result to WorkflowRun status.
@@ -174,3 +651,156 @@ func (k *Executor) SetResults(values []v1alpha1.KeyValue) error {
 		return err
 	})
 }
+
+// PublishPipes copies each file declared in pipes out of container and publishes
+// its content into the Secret/ConfigMap named by the entry's key, so that later
+// stages can reference it via `{{Pipes.<key>}}`. owner is set on objects it
+// creates so they get garbage collected along with the WorkflowRun; an object
+// that already exists is updated with its owner references left unchanged.
+func (k *Executor) PublishPipes(container string, pipes []v1alpha1.PipeSpec, owner meta_v1.OwnerReference) error {
+	for _, p := range pipes {
+		data, err := k.readPipeFile(container, p.File)
+		if err != nil {
+			return fmt.Errorf("publish pipe %s failed: %v", p.Key, err)
+		}
+
+		switch p.Kind {
+		case v1alpha1.PipeKindSecret:
+			err = k.publishToSecret(p.Key, data, owner)
+		case v1alpha1.PipeKindConfigMap:
+			err = k.publishToConfigMap(p.Key, data, owner)
+		default:
+			err = fmt.Errorf("unsupported pipe kind: %s", p.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("publish pipe %s failed: %v", p.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// WaitAndPublishPipes waits for container to reach ContainerStateTerminated and
+// then publishes every Pipe resource declared in resources, i.e. those whose
+// Type is PipeResourceType. This is the call-site the coordinator's stage
+// runner uses to drive PublishPipes: a stage's Pipe outputs are only safe to
+// read once the container that produced them has terminated.
+func (k *Executor) WaitAndPublishPipes(container string, resources []v1alpha1.ResourceSpec, owner meta_v1.OwnerReference, selectors ...common.ContainerSelector) error {
+	if err := k.WaitContainers(common.ContainerStateTerminated, selectors...); err != nil {
+		return err
+	}
+
+	for _, pipes := range pipeOutputsOf(resources) {
+		if err := k.PublishPipes(container, pipes, owner); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pipeOutputsOf returns the Outputs of every PipeResourceType resource in
+// resources, in order, skipping resources of other types.
+func pipeOutputsOf(resources []v1alpha1.ResourceSpec) [][]v1alpha1.PipeSpec {
+	var outputs [][]v1alpha1.PipeSpec
+	for _, r := range resources {
+		if r.Type != v1alpha1.PipeResourceType {
+			continue
+		}
+		outputs = append(outputs, r.Outputs)
+	}
+	return outputs
+}
+
+// readPipeFile copies a file out of container into a temporary local path and
+// reads its content back, enforcing pipeMaxSize.
+func (k *Executor) readPipeFile(container, path string) ([]byte, error) {
+	tmpDir, err := ioutil.TempDir("", "cyclone-pipe-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dst := filepath.Join(tmpDir, filepath.Base(path))
+	if err := k.CopyFromContainer(container, path, dst); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enforcePipeSize(path, len(data)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// enforcePipeSize returns an error if size, the number of bytes read from
+// path, exceeds pipeMaxSize.
+func enforcePipeSize(path string, size int) error {
+	if size > pipeMaxSize {
+		return fmt.Errorf("file %s is %d bytes, exceeds pipe limit of %d bytes", path, size, pipeMaxSize)
+	}
+	return nil
+}
+
+// publishToSecret creates or updates the Secret named key with the given data under key.
+func (k *Executor) publishToSecret(key string, data []byte, owner meta_v1.OwnerReference) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secrets := k.client.CoreV1().Secrets(k.namespace)
+		secret, err := secrets.Get(context.TODO(), key, meta_v1.GetOptions{})
+		if k8s_errors.IsNotFound(err) {
+			_, err = secrets.Create(context.TODO(), &core_v1.Secret{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Name:            key,
+					Namespace:       k.namespace,
+					OwnerReferences: []meta_v1.OwnerReference{owner},
+				},
+				Data: map[string][]byte{key: data},
+			}, meta_v1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = data
+		_, err = secrets.Update(context.TODO(), secret, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+// publishToConfigMap creates or updates the ConfigMap named key with the given data under key.
+func (k *Executor) publishToConfigMap(key string, data []byte, owner meta_v1.OwnerReference) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cms := k.client.CoreV1().ConfigMaps(k.namespace)
+		cm, err := cms.Get(context.TODO(), key, meta_v1.GetOptions{})
+		if k8s_errors.IsNotFound(err) {
+			_, err = cms.Create(context.TODO(), &core_v1.ConfigMap{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Name:            key,
+					Namespace:       k.namespace,
+					OwnerReferences: []meta_v1.OwnerReference{owner},
+				},
+				Data: map[string]string{key: string(data)},
+			}, meta_v1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(data)
+		_, err = cms.Update(context.TODO(), cm, meta_v1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,258 @@
+package k8sapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/caicloud/cyclone/pkg/workflow/common"
+)
+
+func buildTar(t *testing.T, entries map[string]string) *bytes.Buffer {
+	t.Helper()
+	return buildTarWithOwner(t, entries, 0, 0)
+}
+
+func buildTarWithOwner(t *testing.T, entries map[string]string, uid, gid int) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, content := range entries {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+			Uid:  uid,
+			Gid:  gid,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+
+	return buf
+}
+
+func TestUntarPreservesOwnership(t *testing.T) {
+	uid, gid := os.Getuid(), os.Getgid()
+	src := buildTarWithOwner(t, map[string]string{"output.txt": "hello world"}, uid, gid)
+
+	dir, err := ioutil.TempDir("", "untar-test-")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "output.txt")
+	if err := untar(src, "output.txt", dst); err != nil {
+		t.Fatalf("untar: %v", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("stat extracted file: %v", err)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Skip("owner info not available on this platform")
+	}
+	if int(st.Uid) != uid || int(st.Gid) != gid {
+		t.Errorf("extracted file owner = %d:%d, want %d:%d", st.Uid, st.Gid, uid, gid)
+	}
+}
+
+func TestUntarSingleFile(t *testing.T) {
+	src := buildTar(t, map[string]string{"output.txt": "hello world"})
+
+	dir, err := ioutil.TempDir("", "untar-test-")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "output.txt")
+	if err := untar(src, "output.txt", dst); err != nil {
+		t.Fatalf("untar: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read extracted file: %v", err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("got %q, want %q", string(data), "hello world")
+	}
+}
+
+func TestUntarDirectory(t *testing.T) {
+	src := buildTar(t, map[string]string{
+		"dir/a.txt": "a",
+		"dir/b.txt": "b",
+	})
+
+	dir, err := ioutil.TempDir("", "untar-test-")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "dir")
+	if err := untar(src, "dir", dst); err != nil {
+		t.Fatalf("untar: %v", err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "a", "b.txt": "b"} {
+		data, err := ioutil.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: got %q, want %q", name, string(data), want)
+		}
+	}
+}
+
+func TestUntarEmptyStreamIsNotAnError(t *testing.T) {
+	// A stream with no entries (the shape produced when the exec that was
+	// supposed to feed it failed before writing anything) makes untar return
+	// nil, just as a successful copy does; CopyFromContainer relies on the exec
+	// error, not this return value, to detect that case.
+	dir, err := ioutil.TempDir("", "untar-test-")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := untar(bytes.NewReader(nil), "output.txt", filepath.Join(dir, "output.txt")); err != nil {
+		t.Fatalf("untar of empty stream returned unexpected error: %v", err)
+	}
+}
+
+func TestCheckContainersStateNodeLost(t *testing.T) {
+	pod := &core_v1.Pod{
+		Spec: core_v1.PodSpec{
+			Containers: []core_v1.Container{{Name: "build"}},
+		},
+		Status: core_v1.PodStatus{
+			Phase:             core_v1.PodFailed,
+			ContainerStatuses: nil,
+		},
+	}
+
+	reached, err := checkContainersState(pod, common.ContainerStateTerminated, nil)
+	if reached {
+		t.Fatal("a Failed pod with no container statuses should not report reached=true")
+	}
+	if !errors.Is(err, ErrNodeLost) {
+		t.Fatalf("expected ErrNodeLost, got %v", err)
+	}
+}
+
+func TestCheckContainersStateTerminated(t *testing.T) {
+	pod := &core_v1.Pod{
+		Spec: core_v1.PodSpec{
+			Containers: []core_v1.Container{{Name: "build"}},
+		},
+		Status: core_v1.PodStatus{
+			Phase: core_v1.PodSucceeded,
+			ContainerStatuses: []core_v1.ContainerStatus{
+				{
+					Name: "build",
+					State: core_v1.ContainerState{
+						Terminated: &core_v1.ContainerStateTerminated{ExitCode: 0},
+					},
+				},
+			},
+		},
+	}
+
+	reached, err := checkContainersState(pod, common.ContainerStateTerminated, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reached {
+		t.Fatal("expected terminated container to be reached")
+	}
+}
+
+func TestCheckContainersStateNotYetTerminated(t *testing.T) {
+	pod := &core_v1.Pod{
+		Spec: core_v1.PodSpec{
+			Containers: []core_v1.Container{{Name: "build"}},
+		},
+		Status: core_v1.PodStatus{
+			Phase: core_v1.PodRunning,
+			ContainerStatuses: []core_v1.ContainerStatus{
+				{
+					Name:  "build",
+					State: core_v1.ContainerState{Running: &core_v1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+
+	reached, err := checkContainersState(pod, common.ContainerStateTerminated, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reached {
+		t.Fatal("expected a still-running container not to be reported as terminated")
+	}
+}
+
+func TestCheckContainersStateUnsupported(t *testing.T) {
+	pod := &core_v1.Pod{
+		Spec: core_v1.PodSpec{Containers: []core_v1.Container{{Name: "build"}}},
+	}
+
+	if _, err := checkContainersState(pod, common.ContainerState("Bogus"), nil); err == nil {
+		t.Fatal("expected an error for an unsupported expectState")
+	}
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	ts, content := splitLogTimestamp("2026-07-26T10:00:00.123456789Z building step 1")
+	if ts == nil {
+		t.Fatal("expected a parsed timestamp")
+	}
+	if want := "building step 1"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if got, want := ts.Time.Year(), 2026; got != want {
+		t.Errorf("year = %d, want %d", got, want)
+	}
+}
+
+func TestSplitLogTimestampWithoutTimestamp(t *testing.T) {
+	ts, content := splitLogTimestamp("no timestamp here")
+	if ts != nil {
+		t.Fatalf("expected no timestamp to be parsed, got %v", ts)
+	}
+	if want := "no timestamp here"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestSplitLogTimestampEmptyLine(t *testing.T) {
+	ts, content := splitLogTimestamp("")
+	if ts != nil {
+		t.Fatalf("expected no timestamp for an empty line, got %v", ts)
+	}
+	if content != "" {
+		t.Errorf("content = %q, want empty", content)
+	}
+}
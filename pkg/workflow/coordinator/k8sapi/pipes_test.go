@@ -0,0 +1,133 @@
+package k8sapi
+
+import (
+	"context"
+	"testing"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func newTestExecutor(client *fake.Clientset, namespace string) *Executor {
+	return &Executor{client: client, namespace: namespace}
+}
+
+func TestPublishToSecretCreatesSecret(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	k := newTestExecutor(client, "ci")
+	owner := meta_v1.OwnerReference{Name: "wfr-1", Kind: "WorkflowRun"}
+
+	if err := k.publishToSecret("tls-cert", []byte("cert-data"), owner); err != nil {
+		t.Fatalf("publishToSecret: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets("ci").Get(context.TODO(), "tls-cert", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	if string(secret.Data["tls-cert"]) != "cert-data" {
+		t.Errorf("secret data = %q, want %q", secret.Data["tls-cert"], "cert-data")
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "wfr-1" {
+		t.Errorf("owner reference not set: %+v", secret.OwnerReferences)
+	}
+}
+
+func TestPublishToSecretLeavesOtherSecretsAlone(t *testing.T) {
+	client := fake.NewSimpleClientset(&core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "shared", Namespace: "ci"},
+		Data:       map[string][]byte{"other-key": []byte("unrelated")},
+	})
+	k := newTestExecutor(client, "ci")
+
+	if err := k.publishToSecret("tls-cert", []byte("cert-data"), meta_v1.OwnerReference{}); err != nil {
+		t.Fatalf("publishToSecret: %v", err)
+	}
+
+	shared, err := client.CoreV1().Secrets("ci").Get(context.TODO(), "shared", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get pre-existing secret: %v", err)
+	}
+	if string(shared.Data["other-key"]) != "unrelated" {
+		t.Errorf("pre-existing secret was modified: %+v", shared.Data)
+	}
+
+	secret, err := client.CoreV1().Secrets("ci").Get(context.TODO(), "tls-cert", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	if string(secret.Data["tls-cert"]) != "cert-data" {
+		t.Errorf("secret data = %q, want %q", secret.Data["tls-cert"], "cert-data")
+	}
+}
+
+func TestPublishToSecretMergesIntoExistingObject(t *testing.T) {
+	client := fake.NewSimpleClientset(&core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "pipes", Namespace: "ci"},
+		Data:       map[string][]byte{"existing-key": []byte("existing-value")},
+	})
+	k := newTestExecutor(client, "ci")
+
+	if err := k.publishToSecret("pipes", []byte("new-value"), meta_v1.OwnerReference{}); err != nil {
+		t.Fatalf("publishToSecret: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets("ci").Get(context.TODO(), "pipes", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	if string(secret.Data["existing-key"]) != "existing-value" {
+		t.Errorf("existing key was dropped: %+v", secret.Data)
+	}
+	if string(secret.Data["pipes"]) != "new-value" {
+		t.Errorf("new key missing: %+v", secret.Data)
+	}
+}
+
+func TestPublishToConfigMapCreatesConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	k := newTestExecutor(client, "ci")
+
+	if err := k.publishToConfigMap("rendered-config", []byte("key: value"), meta_v1.OwnerReference{}); err != nil {
+		t.Fatalf("publishToConfigMap: %v", err)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("ci").Get(context.TODO(), "rendered-config", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get configmap: %v", err)
+	}
+	if cm.Data["rendered-config"] != "key: value" {
+		t.Errorf("configmap data = %q, want %q", cm.Data["rendered-config"], "key: value")
+	}
+}
+
+func TestEnforcePipeSize(t *testing.T) {
+	if err := enforcePipeSize("f", pipeMaxSize); err != nil {
+		t.Errorf("exactly at limit should be allowed: %v", err)
+	}
+	if err := enforcePipeSize("f", pipeMaxSize+1); err == nil {
+		t.Error("expected an error for a file over the pipe size limit")
+	}
+}
+
+func TestPipeOutputsOf(t *testing.T) {
+	resources := []v1alpha1.ResourceSpec{
+		{Type: v1alpha1.GitResourceType},
+		{Type: v1alpha1.PipeResourceType, Outputs: []v1alpha1.PipeSpec{{Key: "a"}}},
+		{Type: v1alpha1.PipeResourceType, Outputs: []v1alpha1.PipeSpec{{Key: "b"}, {Key: "c"}}},
+	}
+
+	outputs := pipeOutputsOf(resources)
+	if len(outputs) != 2 {
+		t.Fatalf("got %d pipe output groups, want 2", len(outputs))
+	}
+	if len(outputs[0]) != 1 || outputs[0][0].Key != "a" {
+		t.Errorf("outputs[0] = %+v", outputs[0])
+	}
+	if len(outputs[1]) != 2 || outputs[1][0].Key != "b" || outputs[1][1].Key != "c" {
+		t.Errorf("outputs[1] = %+v", outputs[1])
+	}
+}
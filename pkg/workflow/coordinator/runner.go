@@ -0,0 +1,47 @@
+// Package coordinator ties together the pieces of k8sapi.Executor that make up
+// a stage's run: waiting on its container, publishing Pipe resource outputs,
+// and resolving the {{Pipes.X}} references later stages use to consume them.
+package coordinator
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/workflow/common"
+	"github.com/caicloud/cyclone/pkg/workflow/coordinator/k8sapi"
+)
+
+// StageRunner drives the Pipe-resource-related part of a stage's lifecycle:
+// waiting for the container that produces pipe outputs to terminate,
+// publishing those outputs, and resolving {{Pipes.X}} references in specs
+// that consume them.
+type StageRunner struct {
+	executor  *k8sapi.Executor
+	container string
+	resources []v1alpha1.ResourceSpec
+	owner     meta_v1.OwnerReference
+}
+
+// NewStageRunner creates a StageRunner for container, whose resources may
+// declare Pipe outputs to publish under owner once it terminates.
+func NewStageRunner(executor *k8sapi.Executor, container string, resources []v1alpha1.ResourceSpec, owner meta_v1.OwnerReference) *StageRunner {
+	return &StageRunner{
+		executor:  executor,
+		container: container,
+		resources: resources,
+		owner:     owner,
+	}
+}
+
+// Terminate waits for the stage container to reach ContainerStateTerminated
+// and publishes any Pipe resource outputs it produced.
+func (r *StageRunner) Terminate(selectors ...common.ContainerSelector) error {
+	return r.executor.WaitAndPublishPipes(r.container, r.resources, r.owner, selectors...)
+}
+
+// ResolveStageSpec substitutes {{Pipes.<key>}} references in a later stage's
+// spec with the values published by earlier Pipe resources, analogous to how
+// SetResults' key/value outputs are substituted into later stage specs today.
+func (r *StageRunner) ResolveStageSpec(spec string, pipes map[string]string) string {
+	return common.SubstitutePipeRefs(spec, pipes)
+}
@@ -0,0 +1,16 @@
+package coordinator
+
+import (
+	"testing"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStageRunnerResolveStageSpec(t *testing.T) {
+	r := NewStageRunner(nil, "build", nil, meta_v1.OwnerReference{})
+
+	got := r.ResolveStageSpec("cert: {{Pipes.tls-cert}}", map[string]string{"tls-cert": "abc"})
+	if want := "cert: abc"; got != want {
+		t.Errorf("ResolveStageSpec() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AutoCaptureOnFailure collects a support bundle for workflowRun and writes it
+// to destDir as <workflowRun>-support-bundle.zip. It does not check the
+// WorkflowRun's phase and is not called by the workflow controller; the caller
+// decides when to run it and where destDir lives.
+func AutoCaptureOnFailure(ctx context.Context, collector *Collector, workflowRun, destDir string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(destDir, fmt.Sprintf("%s-support-bundle.zip", workflowRun))
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := collector.Collect(ctx, workflowRun, f, nil); err != nil {
+		log.WithField("workflowrun", workflowRun).Errorf("auto-capture support bundle failed: %v", err)
+		return err
+	}
+
+	log.WithField("workflowrun", workflowRun).WithField("path", dst).Info("captured support bundle after terminal failure")
+	return nil
+}
@@ -0,0 +1,279 @@
+// Package support assembles a zip "support bundle" for a WorkflowRun: its CRDs,
+// Pod specs, recent Events, container logs and the coordinator's own logs. It
+// is modeled after Talos' `talosctl support` bundle, so an operator debugging a
+// failed pipeline in a customer cluster can collect everything needed for
+// incident response with a single call.
+package support
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	core_v1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/yaml"
+
+	"github.com/caicloud/cyclone/pkg/util/k8s"
+)
+
+// Progress reports the bundle collector's advancement, mirroring Talos'
+// bundle.Progress so callers can render a progress bar or log line per source.
+type Progress struct {
+	// Source names the item currently being collected, e.g. "pod/build-xyz" or
+	// "logs/build-xyz/clone".
+	Source string
+	// Error is set when collecting Source failed; collection continues with
+	// the remaining sources rather than aborting the whole bundle.
+	Error error
+}
+
+// CRDGetter fetches the Cyclone custom resources referenced by a WorkflowRun.
+// It is satisfied by the generated Cyclone clientset; kept as an interface
+// here so this package doesn't need to depend on the full client wiring.
+//
+// A WorkflowRun fans out into many Resource and Stage objects of its own,
+// each with a distinct name (not the WorkflowRun's name), so those are listed
+// by owning workflowrun rather than fetched by name.
+type CRDGetter interface {
+	GetWorkflowRun(namespace, name string) (runtime.Object, error)
+	GetWorkflow(namespace, name string) (runtime.Object, error)
+	ListResources(namespace, workflowRun string) ([]runtime.Object, error)
+	ListStages(namespace, workflowRun string) ([]runtime.Object, error)
+}
+
+// Collector assembles support bundles for WorkflowRuns.
+type Collector struct {
+	client             k8s.Interface
+	crds               CRDGetter
+	namespace          string
+	coordinatorLogPath string
+}
+
+// NewCollector creates a Collector that gathers diagnostics for WorkflowRuns in
+// namespace. coordinatorLogPath, if non-empty, is included verbatim in the bundle.
+func NewCollector(client k8s.Interface, crds CRDGetter, namespace, coordinatorLogPath string) *Collector {
+	return &Collector{
+		client:             client,
+		crds:               crds,
+		namespace:          namespace,
+		coordinatorLogPath: coordinatorLogPath,
+	}
+}
+
+// Collect streams a zip archive of diagnostics for workflowRun into w, reporting
+// each step on progressCh if non-nil. It writes entries as it goes rather than
+// buffering them, so large log volumes don't blow memory. Errors collecting an
+// individual source are reported on progressCh and otherwise do not stop the
+// remaining collection.
+func (c *Collector) Collect(ctx context.Context, workflowRun string, w io.Writer, progressCh chan<- Progress) error {
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	report := func(source string, err error) {
+		if err != nil {
+			log.WithField("workflowrun", workflowRun).WithField("source", source).Errorf("collect support bundle item failed: %v", err)
+		}
+		if progressCh != nil {
+			progressCh <- Progress{Source: source, Error: err}
+		}
+	}
+
+	c.collectManifest(zw, workflowRun, report)
+	c.collectCRDs(zw, workflowRun, report)
+
+	pods, err := c.client.CoreV1().Pods(c.namespace).List(ctx, meta_v1.ListOptions{
+		LabelSelector: fmt.Sprintf("workflowrun=%s", workflowRun),
+	})
+	if err != nil {
+		report("pods", err)
+	} else {
+		for i := range pods.Items {
+			c.collectPod(ctx, zw, &pods.Items[i], report)
+		}
+	}
+
+	c.collectEvents(ctx, zw, workflowRun, report)
+	c.collectCoordinatorLog(zw, report)
+
+	return nil
+}
+
+func (c *Collector) collectManifest(zw *zip.Writer, workflowRun string, report func(string, error)) {
+	const source = "manifest.yaml"
+	manifest := struct {
+		WorkflowRun string         `json:"workflowRun"`
+		Namespace   string         `json:"namespace"`
+		ServerInfo  *version.Info  `json:"serverVersion,omitempty"`
+		Nodes       []core_v1.Node `json:"nodes,omitempty"`
+	}{
+		WorkflowRun: workflowRun,
+		Namespace:   c.namespace,
+	}
+
+	if info, err := c.client.Discovery().ServerVersion(); err == nil {
+		manifest.ServerInfo = info
+	}
+	if nodes, err := c.client.CoreV1().Nodes().List(context.TODO(), meta_v1.ListOptions{}); err == nil {
+		manifest.Nodes = nodes.Items
+	}
+
+	writeYAML(zw, source, manifest, report)
+}
+
+func (c *Collector) collectCRDs(zw *zip.Writer, workflowRun string, report func(string, error)) {
+	if c.crds == nil {
+		return
+	}
+
+	if wfr, err := c.crds.GetWorkflowRun(c.namespace, workflowRun); err != nil {
+		report("crds/workflowrun.yaml", err)
+	} else {
+		writeYAML(zw, "crds/workflowrun.yaml", wfr, report)
+	}
+
+	if wf, err := c.crds.GetWorkflow(c.namespace, workflowRun); err != nil {
+		report("crds/workflow.yaml", err)
+	} else {
+		writeYAML(zw, "crds/workflow.yaml", wf, report)
+	}
+
+	if resources, err := c.crds.ListResources(c.namespace, workflowRun); err != nil {
+		report("crds/resources", err)
+	} else {
+		for _, res := range resources {
+			source := fmt.Sprintf("crds/resources/%s.yaml", objectName(res))
+			writeYAML(zw, source, res, report)
+		}
+	}
+
+	if stages, err := c.crds.ListStages(c.namespace, workflowRun); err != nil {
+		report("crds/stages", err)
+	} else {
+		for _, stage := range stages {
+			source := fmt.Sprintf("crds/stages/%s.yaml", objectName(stage))
+			writeYAML(zw, source, stage, report)
+		}
+	}
+}
+
+// objectName returns obj's name via the apimachinery meta accessor, or
+// "unknown" if obj doesn't carry object metadata.
+func objectName(obj runtime.Object) string {
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return "unknown"
+	}
+	return accessor.GetName()
+}
+
+func (c *Collector) collectPod(ctx context.Context, zw *zip.Writer, pod *core_v1.Pod, report func(string, error)) {
+	source := fmt.Sprintf("pods/%s/spec.yaml", pod.Name)
+	writeYAML(zw, source, pod, report)
+
+	describeSource := fmt.Sprintf("pods/%s/describe.txt", pod.Name)
+	writeString(zw, describeSource, describePod(pod), report)
+
+	containers := append(append([]core_v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		logSource := fmt.Sprintf("pods/%s/logs/%s.log", pod.Name, container.Name)
+		stream, err := c.client.CoreV1().Pods(c.namespace).GetLogs(pod.Name, &core_v1.PodLogOptions{
+			Container: container.Name,
+		}).Stream(ctx)
+		if err != nil {
+			report(logSource, err)
+			continue
+		}
+
+		entry, err := zw.Create(logSource)
+		if err != nil {
+			stream.Close()
+			report(logSource, err)
+			continue
+		}
+		_, err = io.Copy(entry, stream)
+		stream.Close()
+		report(logSource, err)
+	}
+}
+
+func (c *Collector) collectEvents(ctx context.Context, zw *zip.Writer, workflowRun string, report func(string, error)) {
+	const source = "events.yaml"
+	events, err := c.client.CoreV1().Events(c.namespace).List(ctx, meta_v1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s", workflowRun),
+	})
+	if err != nil {
+		report(source, err)
+		return
+	}
+	writeYAML(zw, source, events.Items, report)
+}
+
+func (c *Collector) collectCoordinatorLog(zw *zip.Writer, report func(string, error)) {
+	if c.coordinatorLogPath == "" {
+		return
+	}
+
+	const source = "coordinator.log"
+	f, err := os.Open(c.coordinatorLogPath)
+	if err != nil {
+		report(source, err)
+		return
+	}
+	defer f.Close()
+
+	entry, err := zw.Create(source)
+	if err != nil {
+		report(source, err)
+		return
+	}
+	_, err = io.Copy(entry, f)
+	report(source, err)
+}
+
+// describePod renders a small subset of `kubectl describe pod` equivalent
+// output: phase, conditions and container statuses.
+func describePod(pod *core_v1.Pod) string {
+	out := fmt.Sprintf("Name:\t%s\nNamespace:\t%s\nNode:\t%s\nStatus:\t%s\n", pod.Name, pod.Namespace, pod.Spec.NodeName, pod.Status.Phase)
+
+	out += "Conditions:\n"
+	for _, cond := range pod.Status.Conditions {
+		out += fmt.Sprintf("  %s\t%s\t%s\n", cond.Type, cond.Status, cond.Message)
+	}
+
+	out += "Container Statuses:\n"
+	for _, cs := range pod.Status.ContainerStatuses {
+		out += fmt.Sprintf("  %s\tready=%v\trestarts=%d\tstate=%+v\n", cs.Name, cs.Ready, cs.RestartCount, cs.State)
+	}
+
+	return out
+}
+
+func writeYAML(zw *zip.Writer, source string, obj interface{}, report func(string, error)) {
+	b, err := yaml.Marshal(obj)
+	if err != nil {
+		report(source, err)
+		return
+	}
+	writeBytes(zw, source, b, report)
+}
+
+func writeString(zw *zip.Writer, source, content string, report func(string, error)) {
+	writeBytes(zw, source, []byte(content), report)
+}
+
+func writeBytes(zw *zip.Writer, source string, b []byte, report func(string, error)) {
+	entry, err := zw.Create(source)
+	if err != nil {
+		report(source, err)
+		return
+	}
+	_, err = entry.Write(b)
+	report(source, err)
+}
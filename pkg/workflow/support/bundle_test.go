@@ -0,0 +1,84 @@
+package support
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	core_v1 "k8s.io/api/core/v1"
+)
+
+func TestObjectName(t *testing.T) {
+	pod := &core_v1.Pod{}
+	pod.Name = "build-xyz"
+	if got := objectName(pod); got != "build-xyz" {
+		t.Errorf("objectName() = %q, want %q", got, "build-xyz")
+	}
+}
+
+func TestWriteYAMLAndString(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	var lastErr error
+	report := func(source string, err error) { lastErr = err }
+
+	writeYAML(zw, "crds/workflowrun.yaml", map[string]string{"foo": "bar"}, report)
+	writeString(zw, "pods/build/describe.txt", "Name:\tbuild\n", report)
+	if lastErr != nil {
+		t.Fatalf("unexpected error writing entries: %v", lastErr)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open zip reader: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"crds/workflowrun.yaml", "pods/build/describe.txt"} {
+		if !names[want] {
+			t.Errorf("expected bundle to contain %s, got entries %v", want, names)
+		}
+	}
+}
+
+func TestDescribePodIncludesStatusFields(t *testing.T) {
+	pod := &core_v1.Pod{
+		Status: core_v1.PodStatus{
+			Phase: core_v1.PodRunning,
+			ContainerStatuses: []core_v1.ContainerStatus{
+				{Name: "clone", Ready: true, RestartCount: 1},
+			},
+		},
+	}
+	pod.Name = "build-xyz"
+	pod.Namespace = "ci"
+
+	out := describePod(pod)
+	for _, want := range []string{"build-xyz", "ci", "Running", "clone", "restarts=1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("describePod output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWorkflowRunNameFromPath(t *testing.T) {
+	cases := map[string]string{
+		"/workflowruns/wfr-1/support-bundle": "wfr-1",
+		"workflowruns/wfr-2/support-bundle":  "wfr-2",
+		"/workflowruns/support-bundle":       "",
+		"/something/else":                    "",
+	}
+	for path, want := range cases {
+		if got := workflowRunNameFromPath(path); got != want {
+			t.Errorf("workflowRunNameFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package support
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NewHandler returns an http.Handler implementing
+// `GET /workflowruns/{name}/support-bundle`, meant to be mounted by
+// cycloneserver's router. It streams the zip archive directly to the response
+// so large log volumes don't buffer in memory.
+func NewHandler(collector *Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := workflowRunNameFromPath(r.URL.Path)
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-support-bundle.zip"`, name))
+
+		if err := collector.Collect(r.Context(), name, w, nil); err != nil {
+			log.WithField("workflowrun", name).Errorf("collect support bundle failed: %v", err)
+		}
+	})
+}
+
+// workflowRunNameFromPath extracts {name} from a
+// `/workflowruns/{name}/support-bundle` request path.
+func workflowRunNameFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "workflowruns" || parts[2] != "support-bundle" {
+		return ""
+	}
+	return parts[1]
+}